@@ -0,0 +1,151 @@
+package goBreaker
+
+import (
+	"sync"
+	"time"
+)
+
+// Record reports a completed call's latency and outcome in one step: it
+// feeds latency to the Container's latency signal (see Container.Observe,
+// AdaptiveTripFunc) and then delegates to Succeed, Fail or Timeout exactly
+// as a caller using those directly would. isTimeout distinguishes a timeout
+// from a plain failure, same as TimeoutWithTrip vs FailWithTrip.
+func (b *Breaker) Record(latency time.Duration, err error, isTimeout bool) {
+	outcome := OutcomeSuccess
+	switch {
+	case err == nil:
+	case isTimeout:
+		outcome = OutcomeTimeout
+	default:
+		outcome = OutcomeFail
+	}
+	b.Container.Observe(latency, outcome)
+
+	switch outcome {
+	case OutcomeSuccess:
+		b.Succeed()
+	case OutcomeTimeout:
+		b.Timeout()
+	default:
+		b.Fail()
+	}
+}
+
+// AdaptiveOptions configures AdaptiveTripFunc.
+type AdaptiveOptions struct {
+	// ErrAlpha smooths the error-rate EWMA; typical 0.2-0.3.
+	ErrAlpha float64
+	// LatAlpha smooths the latency EWMA; typical 0.2-0.3.
+	LatAlpha float64
+	// BaselineAlpha smooths the long-run baseline latency EWMA that LatAlpha
+	// is compared against; typical 0.02, i.e. much slower-moving than
+	// LatAlpha so it represents "normal" rather than "right now".
+	BaselineAlpha float64
+	// ErrThreshold trips the breaker once the error-rate EWMA exceeds it.
+	// Defaults to DEFAULT_BREAKER_RATE.
+	ErrThreshold float64
+	// LatencyMultiplier trips the breaker once the latency EWMA exceeds
+	// LatencyMultiplier times the baseline latency EWMA.
+	LatencyMultiplier float64
+	// MinSamples is the minimum samples in the window before the trip
+	// function will consider tripping, same role as RateTripFunc's
+	// minSamples. Defaults to DEFAULT_BREAKER_MINSAMPLES.
+	MinSamples int64
+}
+
+// AdaptiveTripFunc returns a TripFunc that trips on either of two EWMA
+// signals: an error-rate EWMA crossing opts.ErrThreshold, or a latency EWMA
+// exceeding opts.LatencyMultiplier times a slow-moving baseline latency
+// EWMA. The latter catches grey failures — a dependency that's gone slow
+// but isn't erroring, which RateTripFunc/ThresholdTripFunc can't see at
+// all.
+//
+// The EWMAs advance once per completed bucket rather than on every call: on
+// the first evaluation against a Container implementing BucketRotator (i.e.
+// a local, non-distributed breaker), the returned TripFunc registers itself
+// to recompute errEWMA/latEWMA/baselineEWMA each time the window's bucket
+// rotates, and every call just checks the last-computed values. Updating
+// per call instead would, under real traffic, converge every EWMA
+// (especially the deliberately slow-moving baseline) to the instantaneous
+// window value almost immediately, defeating the "latency spike vs.
+// long-term baseline" comparison. Against a Container with no rotation
+// signal (e.g. snapshotContainer, for a distributed breaker), it falls back
+// to recomputing on every call.
+//
+// Each call to AdaptiveTripFunc returns a TripFunc with its own EWMA state,
+// so construct one per Breaker (e.g. pass it as Options.ShouldTrip) rather
+// than sharing a single instance across breakers.
+func AdaptiveTripFunc(opts AdaptiveOptions) TripFunc {
+	if opts.ErrAlpha <= 0 {
+		opts.ErrAlpha = 0.25
+	}
+	if opts.LatAlpha <= 0 {
+		opts.LatAlpha = 0.25
+	}
+	if opts.BaselineAlpha <= 0 {
+		opts.BaselineAlpha = 0.02
+	}
+	if opts.ErrThreshold <= 0 {
+		opts.ErrThreshold = DEFAULT_BREAKER_RATE
+	}
+	if opts.LatencyMultiplier <= 0 {
+		opts.LatencyMultiplier = 3
+	}
+	if opts.MinSamples <= 0 {
+		opts.MinSamples = DEFAULT_BREAKER_MINSAMPLES
+	}
+
+	var mu sync.Mutex
+	var errEWMA, latEWMA, baselineEWMA float64
+	var initialized bool
+	var registered bool
+
+	update := func(m Container) {
+		rate := m.ErrorRate()
+		latency := float64(m.AvgLatency())
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !initialized {
+			errEWMA, latEWMA, baselineEWMA = rate, latency, latency
+			initialized = true
+			return
+		}
+		errEWMA = opts.ErrAlpha*rate + (1-opts.ErrAlpha)*errEWMA
+		latEWMA = opts.LatAlpha*latency + (1-opts.LatAlpha)*latEWMA
+		baselineEWMA = opts.BaselineAlpha*latency + (1-opts.BaselineAlpha)*baselineEWMA
+	}
+
+	return func(m Container) bool {
+		if m.Samples() < opts.MinSamples {
+			return false
+		}
+
+		// A local Breaker passes itself as m; its embedded Container (the
+		// window) is what implements BucketRotator.
+		rc := m
+		if b, ok := m.(*Breaker); ok {
+			rc = b.Container
+		}
+		if rotator, ok := rc.(BucketRotator); ok {
+			if !registered {
+				// First evaluation: prime the EWMAs from the current
+				// window and hook future updates to bucket rollover.
+				update(m)
+				rotator.OnRotate(func() { update(m) })
+				registered = true
+			}
+		} else {
+			update(m)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if errEWMA > opts.ErrThreshold {
+			return true
+		}
+		return baselineEWMA > 0 && latEWMA > opts.LatencyMultiplier*baselineEWMA
+	}
+}
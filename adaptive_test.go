@@ -0,0 +1,71 @@
+package goBreaker
+
+import (
+	"testing"
+	"time"
+)
+
+// TestGreyFailureTripsOnSucceed checks that AdaptiveTripFunc's latency
+// signal, which only moves on successful calls, can trip the breaker from
+// Succeed rather than only from Fail/Timeout. The EWMAs only advance once
+// per rotated bucket (see TestAdaptiveTripFuncUpdatesPerBucketNotPerCall),
+// so the test uses a short BucketTime and sleeps between calls to land each
+// Record in its own bucket.
+func TestGreyFailureTripsOnSucceed(t *testing.T) {
+	b, err := NewBreaker(Options{
+		BucketTime: time.Millisecond,
+		ShouldTrip: AdaptiveTripFunc(AdaptiveOptions{
+			LatAlpha:          0.5,
+			BaselineAlpha:     0.05,
+			LatencyMultiplier: 2,
+			MinSamples:        1,
+		}),
+	})
+	if err != nil {
+		t.Fatalf("NewBreaker: %v", err)
+	}
+
+	b.Record(10*time.Millisecond, nil, false)
+	if b.State() != CLOSED {
+		t.Fatalf("state = %v after baseline latency, want CLOSED", b.State())
+	}
+
+	for i := 0; i < 5 && b.State() == CLOSED; i++ {
+		time.Sleep(2 * time.Millisecond)
+		b.Record(500*time.Millisecond, nil, false)
+	}
+
+	if b.State() != OPEN {
+		t.Fatalf("state = %v after repeated slow successes, want OPEN", b.State())
+	}
+}
+
+// TestAdaptiveTripFuncUpdatesPerBucketNotPerCall checks that the EWMAs
+// driving AdaptiveTripFunc only move when the window's bucket actually
+// rotates, not on every Fail/Succeed/Timeout call - otherwise the
+// deliberately slow-moving baseline EWMA would converge to the
+// instantaneous window value almost immediately under real traffic,
+// defeating the latency-spike-vs-baseline comparison.
+func TestAdaptiveTripFuncUpdatesPerBucketNotPerCall(t *testing.T) {
+	b, err := NewBreaker(Options{
+		BucketTime: time.Hour, // never rotates within the test
+		ShouldTrip: AdaptiveTripFunc(AdaptiveOptions{
+			LatAlpha:          0.9,
+			BaselineAlpha:     0.05,
+			LatencyMultiplier: 2,
+			MinSamples:        1,
+		}),
+	})
+	if err != nil {
+		t.Fatalf("NewBreaker: %v", err)
+	}
+
+	b.Record(10*time.Millisecond, nil, false)
+	for i := 0; i < 20 && b.State() == CLOSED; i++ {
+		b.Record(500*time.Millisecond, nil, false)
+	}
+
+	if b.State() != CLOSED {
+		t.Fatalf("state = %v after many same-bucket slow successes, want CLOSED since the bucket never rotated", b.State())
+	}
+}
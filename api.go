@@ -1,19 +1,27 @@
 package goBreaker
 
 import (
+	"context"
+	"errors"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const (
-	// MinQps determines the MinSamples when using AdjustBreakers func
+	// MinQps determines the MinSamples when using the Start adjust loop
 	DEFAULT_BREAKER_MINQPS = 200
 )
 
 type CircuitBreaker struct {
 	Breakers map[int32]*Breaker
 	Mutex    sync.RWMutex
+
+	instanceCount int32 // atomic; read by the Start adjust loop
+
+	cancel  context.CancelFunc
+	stopped chan struct{}
 }
 
 var BreakerWhitelist = map[int32]bool{}
@@ -28,11 +36,44 @@ func InitCircuitBreakers(cmds []int32, options Options) (cb CircuitBreaker) {
 
 func (b *CircuitBreaker) GetBreaker(cmd int32) *Breaker {
 	b.Mutex.RLock()
-	defer b.Mutex.RUnlock()
 	cb := b.Breakers[cmd]
-	if cb == nil {
-		return b.GenBreaker(cmd, Options{})
+	b.Mutex.RUnlock()
+	if cb != nil {
+		return cb
+	}
+
+	b.Mutex.Lock()
+	defer b.Mutex.Unlock()
+	if cb := b.Breakers[cmd]; cb != nil {
+		return cb
 	}
+	cb = b.GenBreaker(cmd, Options{})
+	b.Breakers[cmd] = cb
+	return cb
+}
+
+// GetBreakerWithStore is like GetBreaker, but constructs (or reuses) a
+// breaker whose state lives in store under key instead of in process
+// memory, so many application instances converge on the same OPEN/HALFOPEN
+// decision for the same cmd.
+func (b *CircuitBreaker) GetBreakerWithStore(cmd int32, options Options, store StateStore, key string) *Breaker {
+	b.Mutex.RLock()
+	cb := b.Breakers[cmd]
+	b.Mutex.RUnlock()
+	if cb != nil {
+		return cb
+	}
+
+	options.Store = store
+	options.StoreKey = key
+
+	b.Mutex.Lock()
+	defer b.Mutex.Unlock()
+	if cb := b.Breakers[cmd]; cb != nil {
+		return cb
+	}
+	cb = b.GenBreaker(cmd, options)
+	b.Breakers[cmd] = cb
 	return cb
 }
 
@@ -46,32 +87,97 @@ func (b *CircuitBreaker) GetAllBreakers() map[int32]*Breaker {
 	return breakers
 }
 
-// when instances >1, you can use AdjustBreakers
-//count means how many instances you have
-func (b *CircuitBreaker) AdjustBreakers(count int, options Options) {
-	var preCount, breakerWindows int
-	windowTime := options.BucketTime * time.Duration(options.BucketNums)
-	breakerWindows = int(windowTime / 1000000000)
+// SetInstanceCount reports the current cluster size to the adjust loop
+// started by Start, so BreakerMinSamples tracks live cluster-size changes.
+// Safe to call at any time, including before Start or concurrently with the
+// adjust loop.
+func (b *CircuitBreaker) SetInstanceCount(n int) {
+	atomic.StoreInt32(&b.instanceCount, int32(n))
+}
+
+// InstanceCount returns the cluster size last reported via SetInstanceCount.
+func (b *CircuitBreaker) InstanceCount() int {
+	return int(atomic.LoadInt32(&b.instanceCount))
+}
+
+// Start begins the breaker-adjustment subsystem: once a minute it
+// recomputes BreakerMinSamples from the instance count (see
+// SetInstanceCount) and applies it in place to every breaker via
+// Breaker.SetMinSamples, so the sliding window, current state and cooling
+// timer survive the recompute.
+//
+// If options.Store is set, counters already converge across instances
+// through the shared store, so the per-instance BreakerMinSamples heuristic
+// (which assumes each instance only sees 1/count of the traffic) no longer
+// applies, and Start is a no-op. Call Stop to end the loop.
+func (b *CircuitBreaker) Start(ctx context.Context, options Options) error {
+	if b.cancel != nil {
+		return errors.New("goBreaker: already started")
+	}
+
+	if options.Store != nil {
+		log.Printf("breaker adjust skipped: distributed store configured")
+		return nil
+	}
 
 	if options.BreakerMinQPS <= 0 {
 		options.BreakerMinQPS = DEFAULT_BREAKER_MINQPS
 	}
-	for {
-		if count == preCount {
-			time.Sleep(time.Minute)
-			continue
+	windowTime := options.BucketTime * time.Duration(options.BucketNums)
+	breakerWindows := int(windowTime / time.Second)
+
+	ctx, cancel := context.WithCancel(ctx)
+	b.cancel = cancel
+	b.stopped = make(chan struct{})
+
+	go b.adjustLoop(ctx, options.BreakerMinQPS, breakerWindows)
+	return nil
+}
+
+// Stop ends the adjust loop started by Start and waits for it to exit. A
+// no-op if Start was never called or Stop already ran.
+func (b *CircuitBreaker) Stop() error {
+	if b.cancel == nil {
+		return nil
+	}
+	b.cancel()
+	<-b.stopped
+	b.cancel = nil
+	return nil
+}
+
+func (b *CircuitBreaker) adjustLoop(ctx context.Context, breakerMinQPS, breakerWindows int) {
+	defer close(b.stopped)
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	var preCount int
+	adjust := func() {
+		count := b.InstanceCount()
+		if count <= 0 || count == preCount {
+			return
 		}
 		preCount = count
-		options.BreakerMinSamples = breakerWindows * options.BreakerMinQPS / count
-		log.Printf("breaker min sample change, instances count: %v, sample: %v", count, options.BreakerMinSamples)
 
-		b.Mutex.Lock()
-		for cmd := range b.Breakers {
-			b.Breakers[cmd] = b.GenBreaker(cmd, options)
+		minSamples := breakerWindows * breakerMinQPS / count
+		log.Printf("breaker min sample change, instances count: %v, sample: %v", count, minSamples)
+
+		b.Mutex.RLock()
+		for _, breaker := range b.Breakers {
+			breaker.SetMinSamples(minSamples)
 		}
-		b.Mutex.Unlock()
+		b.Mutex.RUnlock()
+	}
 
-		time.Sleep(time.Minute)
+	adjust()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			adjust()
+		}
 	}
 }
 
@@ -81,8 +187,13 @@ func (b *CircuitBreaker) GenBreaker(cmd int32, options Options) *Breaker {
 			cmd, oldState.String(), newState.String(),
 			m.Successes(), m.Failures(), m.Timeouts(), m.ErrorRate())
 	}
-	if options.StateChangeHandler == nil {
+	switch options.StateChangeHandler {
+	case nil:
 		options.StateChangeHandler = callback
+	default:
+		// chain rather than replace, so a caller-supplied handler (e.g.
+		// observability.PrometheusCollector) doesn't silently lose this log.
+		options.StateChangeHandler = ChainStateChangeHandlers(callback, options.StateChangeHandler)
 	}
 	defaultBreaker, _ := NewBreaker(options)
 	return defaultBreaker
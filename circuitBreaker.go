@@ -1,7 +1,10 @@
 package goBreaker
 
 import (
+	"errors"
+	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -46,8 +49,24 @@ const (
 	// HALFOPEN_SUCCESSES is the threshold when the breaker is in HALFOPEN;
 	// after secceeding consecutively this times, it will change its state from HALFOPEN to CLOSED;
 	DEFAULT_HALFOPEN_SUCCESSES = 2
+
+	// DEFAULT_BREAKER_RATE is the error rate RateTripFunc trips at when
+	// Options.BreakerRate isn't set.
+	DEFAULT_BREAKER_RATE = 0.5
+
+	// DEFAULT_BREAKER_MINSAMPLES is the minimum window sample count
+	// RateTripFunc requires before it can trip, when Options.BreakerMinSamples
+	// isn't set.
+	DEFAULT_BREAKER_MINSAMPLES = 20
 )
 
+// ErrHalfOpenLimited is returned by IsAllowedErr when the breaker is HALFOPEN
+// and the call is denied by its half-open admission gate
+// (HalfOpenMaxConcurrent, HalfOpenAdmissionRatio, or the default
+// single-probe-per-DetectTimeout gate), as opposed to being denied because the
+// breaker is OPEN.
+var ErrHalfOpenLimited = errors.New("goBreaker: half-open admission limited")
+
 // TripFunc is a function called by a Breaker when error appears and
 // determines whether the breaker should trip
 type TripFunc func(Container) bool
@@ -55,17 +74,49 @@ type TripFunc func(Container) bool
 // StateChangeHandler
 type StateChangeHandler func(oldState, newState State, m Container)
 
+// ChainStateChangeHandlers combines several StateChangeHandlers into one
+// that calls each in turn (nil handlers are skipped), since Options only
+// has a single StateChangeHandler slot. Useful for combining the default
+// log handler GenBreaker installs with, say, the Prometheus handler from
+// the observability subpackage, without either one replacing the other.
+func ChainStateChangeHandlers(handlers ...StateChangeHandler) StateChangeHandler {
+	return func(oldState, newState State, m Container) {
+		for _, h := range handlers {
+			if h != nil {
+				h(oldState, newState, m)
+			}
+		}
+	}
+}
+
 type Breaker struct {
 	Container // contains all success, error and timeout
 	sync.RWMutex
 
-	state           State
-	openTime        time.Time // the time when the breaker become OPEN
-	lastRetryTime   time.Time // last retry time when in HALFOPEN state
-	halfopenSuccess int       // consecutive successes when HALFOPEN
+	state            State
+	openTime         time.Time // the time when the breaker become OPEN
+	lastRetryTime    time.Time // last retry time when in HALFOPEN state
+	halfopenSuccess  int       // consecutive successes when HALFOPEN
+	halfopenInFlight int64     // in-flight probes when HalfOpenMaxConcurrent is used
 
 	options Options
 
+	// rnd is used by HalfOpenAdmissionRatio. The local (non-Store) admission
+	// path already runs under the breaker lock, but the distributed path
+	// doesn't hold it, so rnd also gets its own mutex rather than relying on
+	// that lock.
+	rnd   *rand.Rand
+	rndMu sync.Mutex
+
+	store    StateStore // when set, state lives in the store instead of the fields above
+	storeKey string
+
+	// minSamples backs the default ShouldTrip (RateTripFunc). It's a plain
+	// field rather than baked into a closure so CircuitBreaker's adjust loop
+	// can update it in place via SetMinSamples, instead of rebuilding the
+	// breaker and losing its in-flight window/state.
+	minSamples int64
+
 	now func() time.Time
 }
 
@@ -85,6 +136,44 @@ type Options struct {
 	ShouldTrip         TripFunc // trip callback, default is RateTrip
 	StateChangeHandler StateChangeHandler
 
+	// HalfOpenAdmissionRatio, when set (0.0-1.0), switches HALFOPEN admission
+	// from the default single-probe-per-DetectTimeout gating to probabilistic
+	// admission: isAllowed admits each call with this probability and denies
+	// the rest with ErrHalfOpenLimited. Mutually exclusive with
+	// HalfOpenMaxConcurrent; HalfOpenMaxConcurrent takes precedence if both
+	// are set.
+	HalfOpenAdmissionRatio float64
+
+	// HalfOpenMaxConcurrent, when set (>0), switches HALFOPEN admission to an
+	// in-flight counter: up to this many probes may be outstanding at once,
+	// incremented on admission and decremented in Succeed/Fail/Timeout.
+	HalfOpenMaxConcurrent int
+
+	// Store, when set, moves state, timers and window counters out of this
+	// Breaker's fields and into the given StateStore keyed by StoreKey, so
+	// many app instances converge on the same OPEN/HALFOPEN decision. Leave
+	// nil for the default in-process behavior.
+	Store    StateStore
+	StoreKey string
+
+	// OnAllowed, when set, is called from isAllowed whenever a call is
+	// admitted (CLOSED, or an admitted HALFOPEN probe). Lets callers like
+	// observability.PrometheusCollector count admitted requests without
+	// wrapping IsAllowed themselves.
+	OnAllowed func()
+
+	// OnRejected, when set, is called from isAllowed whenever a call is
+	// denied; err is nil when denied because the breaker is OPEN,
+	// ErrHalfOpenLimited when denied by the HALFOPEN admission gate, or, for
+	// a Store-backed breaker, the error returned by the store.
+	OnRejected func(err error)
+
+	// OnOutcome, when set, is called from Succeed/Fail/Timeout/Record with
+	// the recorded outcome, so callers like observability.PrometheusCollector
+	// can count success/fail/timeout outcomes without a separate manual
+	// Observe call at every Succeed/Fail/Timeout call site.
+	OnOutcome func(outcome Outcome)
+
 	now func() time.Time
 }
 
@@ -122,9 +211,7 @@ func NewBreaker(options Options) (*Breaker, error) {
 		options.BreakerMinSamples = DEFAULT_BREAKER_MINSAMPLES
 	}
 
-	if options.ShouldTrip == nil {
-		options.ShouldTrip = RateTripFunc(options.BreakerRate, int64(options.BreakerMinSamples))
-	}
+	usesDefaultTrip := options.ShouldTrip == nil
 
 	container, err := NewWindowWithOptions(options.BucketTime, options.BucketNums)
 	if err != nil {
@@ -132,22 +219,37 @@ func NewBreaker(options Options) (*Breaker, error) {
 	}
 
 	breaker := &Breaker{
-		Container: container,
-		now:       options.now,
-		state:     CLOSED,
+		Container:  container,
+		now:        options.now,
+		state:      CLOSED,
+		rnd:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		store:      options.Store,
+		storeKey:   options.StoreKey,
+		minSamples: int64(options.BreakerMinSamples),
+	}
+
+	if usesDefaultTrip {
+		options.ShouldTrip = breaker.defaultShouldTrip
 	}
 
 	breaker.options = Options{
-		BucketTime:         options.BucketTime,
-		BucketNums:         options.BucketNums,
-		BreakerRate:        options.BreakerRate,
-		BreakerMinSamples:  options.BreakerMinSamples,
-		CoolingTimeout:     options.CoolingTimeout,
-		DetectTimeout:      options.DetectTimeout,
-		HalfOpenSuccess:    options.HalfOpenSuccess,
-		ShouldTrip:         options.ShouldTrip,
-		StateChangeHandler: options.StateChangeHandler,
-		now:                options.now,
+		BucketTime:             options.BucketTime,
+		BucketNums:             options.BucketNums,
+		BreakerRate:            options.BreakerRate,
+		BreakerMinSamples:      options.BreakerMinSamples,
+		CoolingTimeout:         options.CoolingTimeout,
+		DetectTimeout:          options.DetectTimeout,
+		HalfOpenSuccess:        options.HalfOpenSuccess,
+		ShouldTrip:             options.ShouldTrip,
+		StateChangeHandler:     options.StateChangeHandler,
+		HalfOpenAdmissionRatio: options.HalfOpenAdmissionRatio,
+		HalfOpenMaxConcurrent:  options.HalfOpenMaxConcurrent,
+		Store:                  options.Store,
+		StoreKey:               options.StoreKey,
+		OnAllowed:              options.OnAllowed,
+		OnRejected:             options.OnRejected,
+		OnOutcome:              options.OnOutcome,
+		now:                    options.now,
 	}
 
 	return breaker, nil
@@ -155,10 +257,20 @@ func NewBreaker(options Options) (*Breaker, error) {
 
 // Succeed records a success and decreases the concurrency counter by one
 func (b *Breaker) Succeed() {
+	if b.options.OnOutcome != nil {
+		b.options.OnOutcome(OutcomeSuccess)
+	}
+
+	if b.store != nil {
+		b.succeedDistributed()
+		return
+	}
+
 	b.Lock()
 	switch b.state {
 	case OPEN: // do nothing
 	case HALFOPEN:
+		b.releaseHalfOpenSlot()
 		b.halfopenSuccess++
 		if b.halfopenSuccess == b.options.HalfOpenSuccess {
 			if b.options.StateChangeHandler != nil {
@@ -169,11 +281,36 @@ func (b *Breaker) Succeed() {
 		}
 	case CLOSED:
 		b.Container.Succeed()
+		// A success still needs to reach ShouldTrip: AdaptiveTripFunc's
+		// latency EWMA only moves on successful calls (Container.Observe
+		// only records latency for OutcomeSuccess), so a grey failure -
+		// slow but erroring-free - would never trip if trip were only
+		// evaluated from error().
+		if b.options.ShouldTrip != nil && b.options.ShouldTrip(b) {
+			if b.options.StateChangeHandler != nil {
+				b.options.StateChangeHandler(CLOSED, OPEN, b.Container)
+			}
+			b.openTime = time.Now()
+			b.state = OPEN
+		}
 	}
 	b.Unlock() // don't use defer
 }
 
 func (b *Breaker) error(isTimeout bool, trip TripFunc) {
+	if b.options.OnOutcome != nil {
+		outcome := OutcomeFail
+		if isTimeout {
+			outcome = OutcomeTimeout
+		}
+		b.options.OnOutcome(outcome)
+	}
+
+	if b.store != nil {
+		b.errorDistributed(isTimeout, trip)
+		return
+	}
+
 	b.Lock()
 	if isTimeout {
 		b.Container.Timeout()
@@ -184,6 +321,7 @@ func (b *Breaker) error(isTimeout bool, trip TripFunc) {
 	switch b.state {
 	case OPEN: // do nothing
 	case HALFOPEN: // become OPEN
+		b.releaseHalfOpenSlot()
 		if b.options.StateChangeHandler != nil {
 			b.options.StateChangeHandler(HALFOPEN, OPEN, b.Container)
 		}
@@ -219,34 +357,150 @@ func (b *Breaker) TimeoutWithTrip(trip TripFunc) {
 }
 
 func (b *Breaker) IsAllowed() bool {
+	allowed, _ := b.isAllowed()
+	return allowed
+}
+
+// IsAllowedErr is a companion to IsAllowed that also reports why a call was
+// denied: a nil error means the breaker is simply OPEN, while
+// ErrHalfOpenLimited means the breaker is HALFOPEN but the call was denied by
+// its admission gate (HalfOpenMaxConcurrent, HalfOpenAdmissionRatio, or the
+// default DetectTimeout gate). For a Store-backed breaker, a store error is
+// returned as is, with allowed false.
+func (b *Breaker) IsAllowedErr() (bool, error) {
 	return b.isAllowed()
 }
 
-func (b *Breaker) isAllowed() bool {
+// isAllowed decides admission, then reports the outcome through
+// Options.OnAllowed/OnRejected, if set.
+func (b *Breaker) isAllowed() (bool, error) {
+	allowed, err := b.decideAllowed()
+	switch {
+	case allowed && b.options.OnAllowed != nil:
+		b.options.OnAllowed()
+	case !allowed && b.options.OnRejected != nil:
+		b.options.OnRejected(err)
+	}
+	return allowed, err
+}
+
+func (b *Breaker) decideAllowed() (bool, error) {
+	if b.store != nil {
+		return b.isAllowedDistributed()
+	}
+
 	b.Lock()
 	switch b.state {
 	case OPEN:
 		now := time.Now()
 		if b.openTime.Add(b.options.CoolingTimeout).After(now) {
 			b.Unlock()
-			return false
+			return false, nil
 		}
-		// cooling timeout, then become HALFOPEN
+		// cooling timeout, then become HALFOPEN; route the first probe
+		// through admitHalfOpen too, so HalfOpenAdmissionRatio/
+		// HalfOpenMaxConcurrent bound it the same as every later probe
+		// instead of always admitting it for free.
 		b.state = HALFOPEN
 		b.halfopenSuccess = 0
-		b.lastRetryTime = now
+		b.halfopenInFlight = 0
+		if !b.admitHalfOpen() {
+			b.Unlock()
+			return false, ErrHalfOpenLimited
+		}
 	case HALFOPEN:
-		now := time.Now()
-		if b.lastRetryTime.Add(b.options.DetectTimeout).After(now) {
+		if !b.admitHalfOpen() {
 			b.Unlock()
-			return false
+			return false, ErrHalfOpenLimited
 		}
-		b.lastRetryTime = now
 	case CLOSED:
 	}
 
 	b.Unlock()
-	return true
+	return true, nil
+}
+
+// admitHalfOpen decides whether a HALFOPEN call should be admitted, in
+// priority order: HalfOpenMaxConcurrent, then HalfOpenAdmissionRatio, falling
+// back to the default single-probe-per-DetectTimeout gating.
+// lock should be obtained by the outside function who call it.
+func (b *Breaker) admitHalfOpen() bool {
+	now := time.Now()
+	switch {
+	case b.options.HalfOpenMaxConcurrent > 0:
+		if b.halfopenInFlight >= int64(b.options.HalfOpenMaxConcurrent) {
+			return false
+		}
+		b.halfopenInFlight++
+		b.lastRetryTime = now
+		return true
+	case b.options.HalfOpenAdmissionRatio > 0:
+		if b.randFloat64() >= b.options.HalfOpenAdmissionRatio {
+			return false
+		}
+		b.lastRetryTime = now
+		return true
+	default:
+		if b.lastRetryTime.Add(b.options.DetectTimeout).After(now) {
+			return false
+		}
+		b.lastRetryTime = now
+		return true
+	}
+}
+
+// randFloat64 is a concurrency-safe wrapper around rnd.Float64; see rndMu.
+func (b *Breaker) randFloat64() float64 {
+	b.rndMu.Lock()
+	defer b.rndMu.Unlock()
+	return b.rnd.Float64()
+}
+
+// releaseHalfOpenSlot decrements the in-flight probe counter used by
+// HalfOpenMaxConcurrent; a no-op when that mode isn't configured.
+// lock should be obtained by the outside function who call it.
+func (b *Breaker) releaseHalfOpenSlot() {
+	if b.options.HalfOpenMaxConcurrent > 0 && b.halfopenInFlight > 0 {
+		b.halfopenInFlight--
+	}
+}
+
+// admitHalfOpenSnapshot is the Store-backed equivalent of admitHalfOpen: it
+// applies the same HalfOpenMaxConcurrent / HalfOpenAdmissionRatio /
+// single-probe priority against a StateSnapshot instead of the Breaker's own
+// fields, returning the snapshot to attempt a CompareAndSwapState with.
+func (b *Breaker) admitHalfOpenSnapshot(snap StateSnapshot, now time.Time) (StateSnapshot, bool) {
+	next := snap
+	switch {
+	case b.options.HalfOpenMaxConcurrent > 0:
+		if snap.HalfopenInFlight >= int64(b.options.HalfOpenMaxConcurrent) {
+			return snap, false
+		}
+		next.HalfopenInFlight++
+		next.LastRetryTime = now
+		return next, true
+	case b.options.HalfOpenAdmissionRatio > 0:
+		if b.randFloat64() >= b.options.HalfOpenAdmissionRatio {
+			return snap, false
+		}
+		next.LastRetryTime = now
+		return next, true
+	default:
+		if snap.LastRetryTime.Add(b.options.DetectTimeout).After(now) {
+			return snap, false
+		}
+		next.LastRetryTime = now
+		return next, true
+	}
+}
+
+// releaseHalfOpenInFlight decrements snap.HalfopenInFlight, the Store-backed
+// equivalent of releaseHalfOpenSlot; a no-op when HalfOpenMaxConcurrent isn't
+// configured.
+func releaseHalfOpenInFlight(b *Breaker, snap *StateSnapshot) {
+	if b.options.HalfOpenMaxConcurrent > 0 && snap.HalfopenInFlight > 0 {
+		snap.HalfopenInFlight--
+	}
 }
 
 // returns the breaker's state now
@@ -286,3 +540,167 @@ func RateTripFunc(rate float64, minSamples int64) TripFunc {
 		return samples >= minSamples && m.ErrorRate() >= rate
 	}
 }
+
+// defaultShouldTrip is RateTripFunc bound to b instead of a fixed
+// minSamples, so SetMinSamples can retune it live.
+func (b *Breaker) defaultShouldTrip(m Container) bool {
+	samples := m.Samples()
+	return samples >= atomic.LoadInt64(&b.minSamples) && m.ErrorRate() >= b.options.BreakerRate
+}
+
+// SetMinSamples updates the minimum sample count the default ShouldTrip
+// requires before it can trip, without touching the breaker's state, window
+// or cooling timer. A no-op if the breaker was created with a custom
+// ShouldTrip, since that closure doesn't consult minSamples.
+func (b *Breaker) SetMinSamples(n int) {
+	atomic.StoreInt64(&b.minSamples, int64(n))
+}
+
+// isAllowedDistributed is the Store-backed equivalent of isAllowed: it loads
+// the shared snapshot and retries the whole read-decide-CAS cycle on
+// version conflicts instead of holding b's local lock.
+func (b *Breaker) isAllowedDistributed() (bool, error) {
+	for {
+		snap, err := b.store.LoadState(b.storeKey)
+		if err != nil {
+			return false, err
+		}
+
+		now := time.Now()
+		switch snap.State {
+		case OPEN:
+			if snap.OpenTime.Add(b.options.CoolingTimeout).After(now) {
+				return false, nil
+			}
+			// cooling timeout, then become HALFOPEN; route the first probe
+			// through admitHalfOpenSnapshot too, same as isAllowed's local
+			// decideAllowed, so this probe is bound by
+			// HalfOpenAdmissionRatio/HalfOpenMaxConcurrent instead of always
+			// being admitted for free.
+			transitioned := snap
+			transitioned.State = HALFOPEN
+			transitioned.HalfopenSuccess = 0
+			transitioned.HalfopenInFlight = 0
+			next, admitted := b.admitHalfOpenSnapshot(transitioned, now)
+			ok, err := b.store.CompareAndSwapState(b.storeKey, snap, next)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				continue
+			}
+			if !admitted {
+				return false, ErrHalfOpenLimited
+			}
+			return true, nil
+		case HALFOPEN:
+			next, admitted := b.admitHalfOpenSnapshot(snap, now)
+			if !admitted {
+				return false, ErrHalfOpenLimited
+			}
+			ok, err := b.store.CompareAndSwapState(b.storeKey, snap, next)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				continue
+			}
+			return true, nil
+		default: // CLOSED
+			return true, nil
+		}
+	}
+}
+
+// succeedDistributed is the Store-backed equivalent of Succeed.
+func (b *Breaker) succeedDistributed() {
+	if err := b.store.IncrCounters(b.storeKey, 1, 0, 0, time.Now().UnixNano()); err != nil {
+		return
+	}
+
+	for {
+		snap, err := b.store.LoadState(b.storeKey)
+		if err != nil || snap.State != HALFOPEN {
+			return
+		}
+
+		next := snap
+		releaseHalfOpenInFlight(b, &next)
+		next.HalfopenSuccess++
+		if next.HalfopenSuccess >= b.options.HalfOpenSuccess {
+			next.State = CLOSED
+			next.Successes, next.Failures, next.Timeouts = 0, 0, 0
+		}
+
+		ok, err := b.store.CompareAndSwapState(b.storeKey, snap, next)
+		if err != nil {
+			return
+		}
+		if !ok {
+			continue
+		}
+		if next.State == CLOSED && b.options.StateChangeHandler != nil {
+			b.options.StateChangeHandler(HALFOPEN, CLOSED, snapshotContainer{snap})
+		}
+		return
+	}
+}
+
+// errorDistributed is the Store-backed equivalent of error.
+func (b *Breaker) errorDistributed(isTimeout bool, trip TripFunc) {
+	var fail, timeout int64
+	if isTimeout {
+		timeout = 1
+	} else {
+		fail = 1
+	}
+	if err := b.store.IncrCounters(b.storeKey, 0, fail, timeout, time.Now().UnixNano()); err != nil {
+		return
+	}
+
+	for {
+		snap, err := b.store.LoadState(b.storeKey)
+		if err != nil {
+			return
+		}
+
+		switch snap.State {
+		case OPEN:
+			return
+		case HALFOPEN:
+			next := snap
+			releaseHalfOpenInFlight(b, &next)
+			next.State = OPEN
+			next.OpenTime = time.Now()
+			ok, err := b.store.CompareAndSwapState(b.storeKey, snap, next)
+			if err != nil {
+				return
+			}
+			if !ok {
+				continue
+			}
+			if b.options.StateChangeHandler != nil {
+				b.options.StateChangeHandler(HALFOPEN, OPEN, snapshotContainer{next})
+			}
+			return
+		default: // CLOSED
+			if trip == nil || !trip(snapshotContainer{snap}) {
+				return
+			}
+			next := snap
+			next.State = OPEN
+			next.OpenTime = time.Now()
+			ok, err := b.store.CompareAndSwapState(b.storeKey, snap, next)
+			if err != nil {
+				return
+			}
+			if !ok {
+				continue
+			}
+			if b.options.StateChangeHandler != nil {
+				b.options.StateChangeHandler(CLOSED, OPEN, snapshotContainer{next})
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package goBreaker
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestHalfOpenMaxConcurrentBoundsFirstProbe checks that the very first probe
+// admitted after CoolingTimeout (the OPEN->HALFOPEN transition) is bounded by
+// HalfOpenMaxConcurrent the same as every later HALFOPEN probe, rather than
+// always being admitted for free.
+func TestHalfOpenMaxConcurrentBoundsFirstProbe(t *testing.T) {
+	b, err := NewBreaker(Options{
+		CoolingTimeout:        time.Millisecond,
+		HalfOpenMaxConcurrent: 2,
+	})
+	if err != nil {
+		t.Fatalf("NewBreaker: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		b.Fail()
+	}
+	if b.State() != OPEN {
+		t.Fatalf("breaker did not trip, state = %v", b.State())
+	}
+
+	time.Sleep(2 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	admitted := 0
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := b.IsAllowedErr(); ok {
+				mu.Lock()
+				admitted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if admitted != 2 {
+		t.Fatalf("admitted = %d, want 2 (HalfOpenMaxConcurrent)", admitted)
+	}
+}
+
+// TestHalfOpenAdmissionRatioZeroUsesDefaultGateForFirstProbe checks that an
+// unset HalfOpenAdmissionRatio (0) leaves the OPEN->HALFOPEN transition on the
+// default single-probe gate: the first post-cooldown probe is admitted and the
+// breaker moves to HALFOPEN.
+func TestHalfOpenAdmissionRatioZeroUsesDefaultGateForFirstProbe(t *testing.T) {
+	b, err := NewBreaker(Options{
+		CoolingTimeout:         time.Millisecond,
+		HalfOpenAdmissionRatio: 0,
+	})
+	if err != nil {
+		t.Fatalf("NewBreaker: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		b.Fail()
+	}
+	time.Sleep(2 * time.Millisecond)
+
+	// HalfOpenAdmissionRatio <= 0 falls back to the default single-probe
+	// gate, so the first probe after cooldown is still admitted once.
+	if ok, _ := b.IsAllowedErr(); !ok {
+		t.Fatalf("expected the first post-cooldown probe to be admitted under the default gate")
+	}
+	if b.State() != HALFOPEN {
+		t.Fatalf("state = %v, want HALFOPEN", b.State())
+	}
+}
@@ -32,15 +32,62 @@ type Container interface {
 	Samples() int64           // (timeouts + failures + successes)
 	Counts() (successes, failures, timeouts int64)
 
+	// Observe records a successful call's latency, for trip funcs like
+	// AdaptiveTripFunc that watch for grey failures (slow-but-not-erroring
+	// dependencies) rather than just error rate. Calls with a non-success
+	// outcome are ignored, since their latency isn't informative once
+	// they're already counted by ErrorRate.
+	Observe(latency time.Duration, outcome Outcome)
+	// AvgLatency returns the mean latency recorded via Observe across the
+	// window, or 0 if none has been recorded.
+	AvgLatency() time.Duration
+
 	Reset()
 }
 
+// BucketRotator is implemented by Containers (namely *window) that expose
+// bucket rollover as a discrete event. AdaptiveTripFunc uses it to
+// recompute its EWMAs once per rotated bucket rather than on every single
+// Fail/Succeed/Timeout call. A Container that doesn't implement it (e.g.
+// snapshotContainer, for a distributed breaker) has no rollover signal, so
+// AdaptiveTripFunc falls back to per-call recomputation against it.
+type BucketRotator interface {
+	// OnRotate registers fn to run whenever the window's current bucket
+	// expires and a new one takes its place. Multiple callbacks may be
+	// registered; all run, in registration order.
+	OnRotate(fn func())
+}
+
+// Outcome classifies how a call completed, for Container.Observe.
+type Outcome int
+
+const (
+	OutcomeSuccess Outcome = iota
+	OutcomeFail
+	OutcomeTimeout
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSuccess:
+		return "success"
+	case OutcomeFail:
+		return "fail"
+	case OutcomeTimeout:
+		return "timeout"
+	}
+	return "unknown"
+}
+
 // bucket holds counts of failures and successes
 type bucket struct {
 	failure int64
 	success int64
 	timeout int64
 
+	latencySum   int64 // nanoseconds, successful calls only
+	latencyCount int64
+
 	timeStamp int64 // unix nano time when the bucket was created
 }
 
@@ -49,6 +96,8 @@ func (b *bucket) Reset() {
 	atomic.StoreInt64(&b.failure, 0)
 	atomic.StoreInt64(&b.success, 0)
 	atomic.StoreInt64(&b.timeout, 0)
+	atomic.StoreInt64(&b.latencySum, 0)
+	atomic.StoreInt64(&b.latencyCount, 0)
 	atomic.StoreInt64(&b.timeStamp, time.Now().UnixNano())
 }
 
@@ -80,6 +129,23 @@ func (b *bucket) TimeStamp() int64 {
 	return atomic.LoadInt64(&b.timeStamp)
 }
 
+// Observe records latency for a successful call; other outcomes are ignored.
+func (b *bucket) Observe(latency time.Duration, outcome Outcome) {
+	if outcome != OutcomeSuccess {
+		return
+	}
+	atomic.AddInt64(&b.latencySum, int64(latency))
+	atomic.AddInt64(&b.latencyCount, 1)
+}
+
+func (b *bucket) LatencySum() int64 {
+	return atomic.LoadInt64(&b.latencySum)
+}
+
+func (b *bucket) LatencyCount() int64 {
+	return atomic.LoadInt64(&b.latencyCount)
+}
+
 // window maintains a slice of buckets and increments the failure and success
 // counts of the current bucket
 type window struct {
@@ -94,6 +160,8 @@ type window struct {
 	inWindow   int           // the number of buckets in the window currently
 
 	conseErr int64 //consecutive errors
+
+	rotateCallbacks []func() // see BucketRotator
 }
 
 // NewWindowWithOptions creates a new window
@@ -118,28 +186,92 @@ func NewWindowWithOptions(bucketTime time.Duration, bucketNums int) (Container,
 // Fail records a failure in the current bucket
 func (w *window) Fail() {
 	w.Lock()
-	b := w.latestBucket()
+	b, rotated := w.latestBucket()
 	w.conseErr++
 	w.Unlock()
 	b.Fail()
+	if rotated {
+		w.fireRotate()
+	}
 }
 
 // Success records a success in the current bucket
 func (w *window) Succeed() {
 	w.Lock()
-	b := w.latestBucket()
+	b, rotated := w.latestBucket()
 	w.conseErr = 0
 	w.Unlock()
 	b.Succeed()
+	if rotated {
+		w.fireRotate()
+	}
 }
 
 // Timeout records a timeout in the current bucket
 func (w *window) Timeout() {
 	w.Lock()
-	b := w.latestBucket()
+	b, rotated := w.latestBucket()
 	w.conseErr++
 	w.Unlock()
 	b.Timeout()
+	if rotated {
+		w.fireRotate()
+	}
+}
+
+// Observe records a successful call's latency in the current bucket; see
+// Container.Observe.
+func (w *window) Observe(latency time.Duration, outcome Outcome) {
+	w.Lock()
+	b, rotated := w.latestBucket()
+	w.Unlock()
+	b.Observe(latency, outcome)
+	if rotated {
+		w.fireRotate()
+	}
+}
+
+// OnRotate implements BucketRotator.
+func (w *window) OnRotate(fn func()) {
+	w.Lock()
+	w.rotateCallbacks = append(w.rotateCallbacks, fn)
+	w.Unlock()
+}
+
+// fireRotate runs the registered rotation callbacks outside of w's lock, so
+// a callback calling back into w (e.g. ErrorRate, AvgLatency) doesn't
+// deadlock.
+func (w *window) fireRotate() {
+	w.Lock()
+	callbacks := w.rotateCallbacks
+	w.Unlock()
+	for _, fn := range callbacks {
+		fn()
+	}
+}
+
+// AvgLatency returns the mean latency recorded via Observe across all live
+// buckets, or 0 if none has been recorded.
+func (w *window) AvgLatency() time.Duration {
+	w.Lock()
+	oldest, remain := w.expire()
+	w.Unlock()
+
+	var sum, count int64
+	for remain > 0 {
+		sum += w.buckets[oldest].LatencySum()
+		count += w.buckets[oldest].LatencyCount()
+		oldest++
+		if oldest >= w.bucketNums {
+			oldest = 0
+		}
+		remain--
+	}
+
+	if count == 0 {
+		return 0
+	}
+	return time.Duration(sum / count)
 }
 
 func (w *window) Counts() (successes, failures, timeouts int64) {
@@ -236,9 +368,10 @@ func (w *window) expire() (oldest, inWindow int) {
 	return w.oldest, w.inWindow
 }
 
-// latestBucket returns the latest bucket;
+// latestBucket returns the latest bucket, and whether fetching it just
+// rotated the window onto a newly-reset bucket (see BucketRotator);
 // lock should be obtained by the outside function who call it
-func (w *window) latestBucket() *bucket {
+func (w *window) latestBucket() (b *bucket, rotated bool) {
 	// check or create the lastest bucket
 	lastestBucket := w.buckets[w.latest]
 	stamp := lastestBucket.TimeStamp()
@@ -260,7 +393,8 @@ func (w *window) latestBucket() *bucket {
 			w.inWindow++
 		}
 		w.buckets[w.latest].Reset()
+		rotated = true
 	}
-	b := w.buckets[w.latest]
-	return b
+	b = w.buckets[w.latest]
+	return b, rotated
 }
@@ -0,0 +1,125 @@
+// Package observability wires a Breaker's state changes and request outcomes
+// into Prometheus metrics.
+package observability
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	goBreaker "github.com/JeffreyDing11223/goBreaker"
+)
+
+// Collector registers and holds the breaker metrics: breaker_state{cmd,state},
+// breaker_transitions_total{cmd,from,to}, breaker_requests_total{cmd,outcome}
+// and breaker_error_rate{cmd}. It implements prometheus.Collector, so it can
+// be registered directly with a prometheus.Registerer.
+type Collector struct {
+	state       *prometheus.GaugeVec
+	transitions *prometheus.CounterVec
+	requests    *prometheus.CounterVec
+	errorRate   *prometheus.GaugeVec
+}
+
+// PrometheusCollector creates a Collector namespaced under namespace. cmd
+// isn't part of goBreaker.StateChangeHandler or Options.OnAllowed/OnRejected/
+// OnOutcome (none of them carry the breaker's command), so rather than a
+// single shared handler, PrometheusCollector returns per-cmd constructors:
+// call Handler(cmd) for the StateChangeHandler to install (chain it with any
+// existing handler via goBreaker.ChainStateChangeHandlers, e.g. the log
+// callback GenBreaker installs), and Hooks(cmd) for the
+// OnAllowed/OnRejected/OnOutcome triple to wire into that breaker's Options
+// so breaker_requests_total gets the rejected|success|fail|timeout
+// taxonomy without a separate manual Observe call at every call site.
+func PrometheusCollector(namespace string) *Collector {
+	return &Collector{
+		state: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "breaker_state",
+			Help:      "1 for the breaker's current state, 0 for the others, labeled by cmd and state.",
+		}, []string{"cmd", "state"}),
+		transitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "breaker_transitions_total",
+			Help:      "Total breaker state transitions, labeled by cmd, from and to.",
+		}, []string{"cmd", "from", "to"}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "breaker_requests_total",
+			Help:      "Total calls seen by the breaker, labeled by cmd and outcome (success|fail|timeout|rejected).",
+		}, []string{"cmd", "outcome"}),
+		errorRate: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "breaker_error_rate",
+			Help:      "Error rate of the breaker's window at its last state transition, labeled by cmd.",
+		}, []string{"cmd"}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.state.Describe(ch)
+	c.transitions.Describe(ch)
+	c.requests.Describe(ch)
+	c.errorRate.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.state.Collect(ch)
+	c.transitions.Collect(ch)
+	c.requests.Collect(ch)
+	c.errorRate.Collect(ch)
+}
+
+// Handler returns the goBreaker.StateChangeHandler to install (directly, or
+// chained with another via goBreaker.ChainStateChangeHandlers) on the
+// breaker for cmd, so its state transitions update breaker_state,
+// breaker_transitions_total and breaker_error_rate.
+func (c *Collector) Handler(cmd int32) goBreaker.StateChangeHandler {
+	label := strconv.Itoa(int(cmd))
+	return func(oldState, newState goBreaker.State, m goBreaker.Container) {
+		for _, s := range []goBreaker.State{goBreaker.OPEN, goBreaker.HALFOPEN, goBreaker.CLOSED} {
+			v := 0.0
+			if s == newState {
+				v = 1.0
+			}
+			c.state.WithLabelValues(label, s.String()).Set(v)
+		}
+		c.transitions.WithLabelValues(label, oldState.String(), newState.String()).Inc()
+		c.errorRate.WithLabelValues(label).Set(m.ErrorRate())
+	}
+}
+
+// Hooks returns the Options.OnAllowed/OnRejected/OnOutcome triple for the
+// breaker for cmd, so rejected and completed calls increment
+// breaker_requests_total with the right outcome label
+// (success|fail|timeout|rejected) without any separate manual Observe call.
+// onAllowed is nil: an admitted call is already counted once it completes,
+// via onOutcome, so also counting it on admission would double-count every
+// non-rejected call against breaker_requests_total. Admission counting, if
+// wanted, belongs in its own metric rather than this one. Wire the
+// non-nil two into that breaker's Options, e.g.:
+//
+//	onAllowed, onRejected, onOutcome := collector.Hooks(cmd)
+//	goBreaker.Options{OnAllowed: onAllowed, OnRejected: onRejected, OnOutcome: onOutcome}
+func (c *Collector) Hooks(cmd int32) (onAllowed func(), onRejected func(error), onOutcome func(goBreaker.Outcome)) {
+	label := strconv.Itoa(int(cmd))
+	onRejected = func(err error) {
+		c.requests.WithLabelValues(label, "rejected").Inc()
+	}
+	onOutcome = func(outcome goBreaker.Outcome) {
+		c.requests.WithLabelValues(label, outcome.String()).Inc()
+	}
+	return nil, onRejected, onOutcome
+}
+
+// Observe records an admitted call's outcome (success, fail or timeout)
+// against breaker_requests_total for cmd. Hooks' onOutcome already does this
+// for any breaker whose Options.OnOutcome it was wired into; Observe remains
+// for reporting an outcome that didn't go through that Breaker's
+// Succeed/Fail/Timeout/Record at all (e.g. a caller classifying outcomes
+// from a policy.Breaker wrapping a breaker it doesn't own).
+func (c *Collector) Observe(cmd int32, outcome goBreaker.Outcome) {
+	c.requests.WithLabelValues(strconv.Itoa(int(cmd)), outcome.String()).Inc()
+}
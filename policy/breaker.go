@@ -0,0 +1,66 @@
+package policy
+
+import (
+	"context"
+	"errors"
+
+	goBreaker "github.com/JeffreyDing11223/goBreaker"
+)
+
+// ErrBreakerOpen is returned when a call is denied because the wrapped
+// breaker is OPEN (as opposed to HALFOPEN admission limiting, which surfaces
+// goBreaker.ErrHalfOpenLimited instead).
+var ErrBreakerOpen = errors.New("policy: breaker is open")
+
+// IsTimeoutErrorFunc classifies an error returned by a wrapped call as a
+// timeout, as opposed to a plain failure, so Breaker knows whether to
+// report it to the underlying breaker via Timeout or Fail.
+type IsTimeoutErrorFunc func(error) bool
+
+// BreakerOptions configures a Breaker policy.
+type BreakerOptions struct {
+	// IsTimeoutError classifies errors as timeouts; defaults to matching
+	// context.DeadlineExceeded.
+	IsTimeoutError IsTimeoutErrorFunc
+}
+
+// Breaker is the goBreaker.Breaker policy: it checks IsAllowed before
+// invoking the call, and reports the outcome back to the breaker with
+// Succeed/Fail/Timeout.
+type Breaker struct {
+	breaker *goBreaker.Breaker
+	options BreakerOptions
+}
+
+// NewBreaker wraps an existing *goBreaker.Breaker as a Policy.
+func NewBreaker(b *goBreaker.Breaker, options BreakerOptions) *Breaker {
+	if options.IsTimeoutError == nil {
+		options.IsTimeoutError = func(err error) bool {
+			return errors.Is(err, context.DeadlineExceeded)
+		}
+	}
+	return &Breaker{breaker: b, options: options}
+}
+
+// Wrap implements Policy.
+func (b *Breaker) Wrap(next Handler) Handler {
+	return func(ctx context.Context) (interface{}, error) {
+		if allowed, err := b.breaker.IsAllowedErr(); !allowed {
+			if err != nil {
+				return nil, err
+			}
+			return nil, ErrBreakerOpen
+		}
+
+		result, err := next(ctx)
+		switch {
+		case err == nil:
+			b.breaker.Succeed()
+		case b.options.IsTimeoutError(err):
+			b.breaker.Timeout()
+		default:
+			b.breaker.Fail()
+		}
+		return result, err
+	}
+}
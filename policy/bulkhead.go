@@ -0,0 +1,62 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrBulkheadFull is returned when a call can't acquire a Bulkhead slot
+// within QueueWait.
+var ErrBulkheadFull = errors.New("policy: bulkhead is full")
+
+// BulkheadOptions configures a Bulkhead policy.
+type BulkheadOptions struct {
+	MaxConcurrent int           // max in-flight calls, default 1
+	QueueWait     time.Duration // how long to wait for a free slot before failing, default 0 (no wait)
+}
+
+// Bulkhead caps the number of concurrent in-flight calls with a semaphore.
+type Bulkhead struct {
+	sem     chan struct{}
+	options BulkheadOptions
+}
+
+// NewBulkhead creates a Bulkhead policy with the given options.
+func NewBulkhead(options BulkheadOptions) *Bulkhead {
+	if options.MaxConcurrent <= 0 {
+		options.MaxConcurrent = 1
+	}
+	return &Bulkhead{
+		sem:     make(chan struct{}, options.MaxConcurrent),
+		options: options,
+	}
+}
+
+// Wrap implements Policy.
+func (bh *Bulkhead) Wrap(next Handler) Handler {
+	return func(ctx context.Context) (interface{}, error) {
+		if bh.options.QueueWait <= 0 {
+			// QueueWait's default is "no wait": fail fast on a full
+			// semaphore instead of blocking on the caller's ctx, which
+			// may never cancel.
+			select {
+			case bh.sem <- struct{}{}:
+			default:
+				return nil, ErrBulkheadFull
+			}
+		} else {
+			acquireCtx, cancel := context.WithTimeout(ctx, bh.options.QueueWait)
+			defer cancel()
+
+			select {
+			case bh.sem <- struct{}{}:
+			case <-acquireCtx.Done():
+				return nil, ErrBulkheadFull
+			}
+		}
+		defer func() { <-bh.sem }()
+
+		return next(ctx)
+	}
+}
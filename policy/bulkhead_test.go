@@ -0,0 +1,70 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestBulkheadFailsFastWithoutQueueWait checks that the default
+// QueueWait<=0 ("no wait") denies a call immediately once the semaphore is
+// full, instead of blocking on the caller's (possibly uncancelable) ctx.
+func TestBulkheadFailsFastWithoutQueueWait(t *testing.T) {
+	bh := NewBulkhead(BulkheadOptions{MaxConcurrent: 1})
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	h := bh.Wrap(func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})
+
+	go h(context.Background())
+	<-started
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := bh.Wrap(func(ctx context.Context) (interface{}, error) {
+			return nil, nil
+		})(context.Background())
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrBulkheadFull) {
+			t.Fatalf("err = %v, want ErrBulkheadFull", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second call blocked instead of failing fast")
+	}
+
+	close(release)
+}
+
+func TestBulkheadQueueWaitBlocksUntilTimeout(t *testing.T) {
+	bh := NewBulkhead(BulkheadOptions{MaxConcurrent: 1, QueueWait: 20 * time.Millisecond})
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go bh.Wrap(func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})(context.Background())
+	<-started
+	defer close(release)
+
+	start := time.Now()
+	_, err := bh.Wrap(func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	})(context.Background())
+	if !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("err = %v, want ErrBulkheadFull", err)
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Fatalf("returned after %v, want to have waited out QueueWait", elapsed)
+	}
+}
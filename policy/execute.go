@@ -0,0 +1,29 @@
+package policy
+
+import (
+	"context"
+
+	goBreaker "github.com/JeffreyDing11223/goBreaker"
+)
+
+// Execute runs fn through e's policy chain and returns a typed result. This
+// is a package-level function rather than a method on Executor because Go
+// doesn't support generic methods.
+func Execute[T any](ctx context.Context, e *Executor, fn func(context.Context) (T, error)) (T, error) {
+	raw, err := e.execute(ctx, func(ctx context.Context) (interface{}, error) {
+		return fn(ctx)
+	})
+	var zero T
+	if raw == nil {
+		return zero, err
+	}
+	return raw.(T), err
+}
+
+// ExecuteWithBreaker runs fn guarded by a single Breaker policy; shorthand
+// for Execute(ctx, NewExecutor().WithBreaker(NewBreaker(b, options)), fn)
+// for callers that just want a breaker around an RPC without building a
+// full Executor chain.
+func ExecuteWithBreaker[T any](ctx context.Context, b *goBreaker.Breaker, options BreakerOptions, fn func(context.Context) (T, error)) (T, error) {
+	return Execute(ctx, NewExecutor().WithBreaker(NewBreaker(b, options)), fn)
+}
@@ -0,0 +1,25 @@
+package policy
+
+import "context"
+
+// Fallback returns an alternate value/error when the wrapped call fails.
+type Fallback struct {
+	fn func(ctx context.Context, err error) (interface{}, error)
+}
+
+// NewFallback creates a Fallback policy that invokes fn with the failing
+// error when the wrapped call returns a non-nil error.
+func NewFallback(fn func(ctx context.Context, err error) (interface{}, error)) *Fallback {
+	return &Fallback{fn: fn}
+}
+
+// Wrap implements Policy.
+func (f *Fallback) Wrap(next Handler) Handler {
+	return func(ctx context.Context) (interface{}, error) {
+		result, err := next(ctx)
+		if err == nil {
+			return result, nil
+		}
+		return f.fn(ctx, err)
+	}
+}
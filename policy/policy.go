@@ -0,0 +1,72 @@
+// Package policy composes ordered execution policies — retry, timeout,
+// bulkhead, fallback and circuit breaking — around a call, so callers don't
+// have to hand-roll the IsAllowed/Succeed/Fail dance around every RPC.
+package policy
+
+import "context"
+
+// Handler is a unit of work threaded through a chain of Policies. It mirrors
+// the func(ctx) (T, error) shape callers want, but is untyped so Policies
+// can be combined independently of the generic Execute wrapper.
+type Handler func(ctx context.Context) (interface{}, error)
+
+// Policy wraps a Handler with additional behavior (retrying, timing out,
+// limiting concurrency, circuit breaking, ...) and returns the Handler to
+// invoke in its place.
+type Policy interface {
+	Wrap(next Handler) Handler
+}
+
+// Executor chains an ordered list of Policies around a call. Policies apply
+// in the order they were added, so the first one added is outermost:
+// NewExecutor().WithRetry(r).WithBreaker(b) retries the whole
+// breaker-guarded call, not just the innermost attempt.
+type Executor struct {
+	policies []Policy
+}
+
+// NewExecutor creates an empty Executor; calls made through it run fn
+// directly until policies are added with the With* methods.
+func NewExecutor() *Executor {
+	return &Executor{}
+}
+
+// WithRetry adds a Retry policy to the chain.
+func (e *Executor) WithRetry(r *Retry) *Executor {
+	e.policies = append(e.policies, r)
+	return e
+}
+
+// WithTimeout adds a Timeout policy to the chain.
+func (e *Executor) WithTimeout(t *Timeout) *Executor {
+	e.policies = append(e.policies, t)
+	return e
+}
+
+// WithBulkhead adds a Bulkhead policy to the chain.
+func (e *Executor) WithBulkhead(bh *Bulkhead) *Executor {
+	e.policies = append(e.policies, bh)
+	return e
+}
+
+// WithFallback adds a Fallback policy to the chain.
+func (e *Executor) WithFallback(f *Fallback) *Executor {
+	e.policies = append(e.policies, f)
+	return e
+}
+
+// WithBreaker adds a Breaker policy to the chain.
+func (e *Executor) WithBreaker(b *Breaker) *Executor {
+	e.policies = append(e.policies, b)
+	return e
+}
+
+// execute wraps fn with every policy in reverse order, so the first policy
+// added ends up outermost, then invokes the resulting Handler.
+func (e *Executor) execute(ctx context.Context, fn Handler) (interface{}, error) {
+	h := fn
+	for i := len(e.policies) - 1; i >= 0; i-- {
+		h = e.policies[i].Wrap(h)
+	}
+	return h(ctx)
+}
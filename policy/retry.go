@@ -0,0 +1,82 @@
+package policy
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryOptions configures a Retry policy.
+type RetryOptions struct {
+	MaxAttempts int              // total attempts including the first, default 3
+	BaseDelay   time.Duration    // base of the exponential backoff, default 50ms
+	MaxDelay    time.Duration    // cap on the backoff delay, default 2s
+	RetryIf     func(error) bool // default: retry on any non-nil error
+}
+
+// Retry retries a failed call with exponential backoff and full jitter.
+type Retry struct {
+	options RetryOptions
+
+	// rndMu guards rnd: a single Retry is typically constructed once and
+	// its Wrap'd Handler shared across concurrent callers, but rand.Rand
+	// isn't safe for concurrent use.
+	rndMu sync.Mutex
+	rnd   *rand.Rand
+}
+
+// NewRetry creates a Retry policy with the given options, filling in
+// defaults for zero fields.
+func NewRetry(options RetryOptions) *Retry {
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 3
+	}
+	if options.BaseDelay <= 0 {
+		options.BaseDelay = 50 * time.Millisecond
+	}
+	if options.MaxDelay <= 0 {
+		options.MaxDelay = 2 * time.Second
+	}
+	if options.RetryIf == nil {
+		options.RetryIf = func(err error) bool { return err != nil }
+	}
+	return &Retry{
+		options: options,
+		rnd:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Wrap implements Policy.
+func (r *Retry) Wrap(next Handler) Handler {
+	return func(ctx context.Context) (interface{}, error) {
+		var result interface{}
+		var err error
+		for attempt := 0; attempt < r.options.MaxAttempts; attempt++ {
+			if attempt > 0 {
+				select {
+				case <-time.After(r.backoff(attempt)):
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			}
+			result, err = next(ctx)
+			if err == nil || !r.options.RetryIf(err) {
+				return result, err
+			}
+		}
+		return result, err
+	}
+}
+
+// backoff returns the jittered delay before the given retry attempt
+// (1-indexed), exponential in attempt and capped at MaxDelay.
+func (r *Retry) backoff(attempt int) time.Duration {
+	d := r.options.BaseDelay * time.Duration(int64(1)<<uint(attempt-1))
+	if d <= 0 || d > r.options.MaxDelay {
+		d = r.options.MaxDelay
+	}
+	r.rndMu.Lock()
+	defer r.rndMu.Unlock()
+	return time.Duration(r.rnd.Int63n(int64(d) + 1))
+}
@@ -0,0 +1,55 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRetryConcurrentWrapUsers exercises the same *Retry from many
+// goroutines at once, the way a single Retry shared across an Executor's
+// concurrent callers would. Run with -race to catch a regression of the
+// shared rand.Rand.
+func TestRetryConcurrentWrapUsers(t *testing.T) {
+	r := NewRetry(RetryOptions{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond})
+
+	attempt := func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("boom")
+	}
+	h := r.Wrap(attempt)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := h(context.Background()); err == nil {
+				t.Error("expected the final error to surface")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRetrySucceedsWithoutExhaustingAttempts(t *testing.T) {
+	r := NewRetry(RetryOptions{MaxAttempts: 3, BaseDelay: time.Millisecond})
+
+	calls := 0
+	h := r.Wrap(func(ctx context.Context) (interface{}, error) {
+		calls++
+		if calls < 2 {
+			return nil, errors.New("transient")
+		}
+		return "ok", nil
+	})
+
+	result, err := h(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "ok" || calls != 2 {
+		t.Fatalf("result=%v calls=%d, want ok/2", result, calls)
+	}
+}
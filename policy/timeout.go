@@ -0,0 +1,25 @@
+package policy
+
+import (
+	"context"
+	"time"
+)
+
+// Timeout enforces a per-attempt deadline around a call.
+type Timeout struct {
+	duration time.Duration
+}
+
+// NewTimeout creates a Timeout policy with the given per-attempt duration.
+func NewTimeout(d time.Duration) *Timeout {
+	return &Timeout{duration: d}
+}
+
+// Wrap implements Policy.
+func (t *Timeout) Wrap(next Handler) Handler {
+	return func(ctx context.Context) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, t.duration)
+		defer cancel()
+		return next(ctx)
+	}
+}
@@ -0,0 +1,158 @@
+// Package redisstore is a goBreaker.StateStore backend that keeps a
+// breaker's state, timers and window counters in a single Redis hash per
+// breaker key, so many application instances converge on the same
+// OPEN/HALFOPEN/CLOSED decision instead of each maintaining an independent
+// in-process window.
+package redisstore
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	goBreaker "github.com/JeffreyDing11223/goBreaker"
+)
+
+// field names within the per-key Redis hash.
+const (
+	fieldState            = "state"
+	fieldOpenTime         = "open_time"
+	fieldLastRetryTime    = "last_retry_time"
+	fieldHalfopenSuccess  = "halfopen_success"
+	fieldHalfopenInFlight = "halfopen_inflight"
+	fieldSuccesses        = "successes"
+	fieldFailures         = "failures"
+	fieldTimeouts         = "timeouts"
+	fieldBucketTS         = "bucket_ts"
+	fieldVersion          = "version"
+)
+
+// casScript atomically replaces key's hash with the new field values if and
+// only if its current version still matches oldVersion, bumping the
+// version by one on success. It returns 1 on success, 0 if the version has
+// since changed (or the key is missing and oldVersion != 0).
+var casScript = redis.NewScript(`
+local cur = redis.call('HGET', KEYS[1], 'version')
+if cur == false then cur = '0' end
+if cur ~= ARGV[1] then
+	return 0
+end
+redis.call('HSET', KEYS[1],
+	'state', ARGV[2],
+	'open_time', ARGV[3],
+	'last_retry_time', ARGV[4],
+	'halfopen_success', ARGV[5],
+	'successes', ARGV[6],
+	'failures', ARGV[7],
+	'timeouts', ARGV[8],
+	'halfopen_inflight', ARGV[9],
+	'version', tonumber(ARGV[1]) + 1)
+return 1
+`)
+
+// incrScript rolls the window counters for key forward by succ/fail/timeout,
+// resetting them first if bucketTS is newer than the last recorded rotation
+// by more than windowNanos (ARGV[4]), so the counters behave like a single
+// rolling window rather than an ever-growing total. It also bumps version,
+// so a CompareAndSwapState racing this call sees its loaded snapshot as
+// stale and retries with the incremented counters instead of overwriting
+// them with whatever it read before this call landed.
+var incrScript = redis.NewScript(`
+local bucketTS = tonumber(ARGV[1])
+local windowNanos = tonumber(ARGV[4])
+local lastTS = tonumber(redis.call('HGET', KEYS[1], 'bucket_ts') or '0')
+if lastTS == 0 then
+	redis.call('HSET', KEYS[1], 'state', '2')
+end
+if bucketTS - lastTS > windowNanos then
+	redis.call('HSET', KEYS[1], 'successes', 0, 'failures', 0, 'timeouts', 0, 'bucket_ts', bucketTS)
+end
+redis.call('HINCRBY', KEYS[1], 'successes', ARGV[2])
+redis.call('HINCRBY', KEYS[1], 'failures', ARGV[3])
+redis.call('HINCRBY', KEYS[1], 'timeouts', ARGV[5])
+redis.call('HINCRBY', KEYS[1], 'version', 1)
+return 1
+`)
+
+// Store is a goBreaker.StateStore backed by Redis, one hash key per breaker.
+type Store struct {
+	client redis.UniversalClient
+
+	// WindowTime bounds how long IncrCounters accumulates failures/successes
+	// into the same window before rotating it, mirroring the
+	// BucketTime*BucketNums window a local Container keeps. Defaults to 10s,
+	// matching goBreaker's own DEFAULT_BUCKET_TIME*DEFAULT_BUCKET_NUMS.
+	WindowTime time.Duration
+}
+
+// NewStore creates a Store using client, one Redis hash per breaker key.
+func NewStore(client redis.UniversalClient) *Store {
+	return &Store{client: client, WindowTime: 10 * time.Second}
+}
+
+// LoadState implements goBreaker.StateStore.
+func (s *Store) LoadState(key string) (goBreaker.StateSnapshot, error) {
+	ctx := context.Background()
+	res, err := s.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return goBreaker.StateSnapshot{}, err
+	}
+	if len(res) == 0 {
+		return goBreaker.StateSnapshot{State: goBreaker.CLOSED}, nil
+	}
+
+	snap := goBreaker.StateSnapshot{
+		State:            goBreaker.State(parseInt(res[fieldState])),
+		OpenTime:         time.Unix(0, parseInt(res[fieldOpenTime])),
+		LastRetryTime:    time.Unix(0, parseInt(res[fieldLastRetryTime])),
+		HalfopenSuccess:  int(parseInt(res[fieldHalfopenSuccess])),
+		HalfopenInFlight: parseInt(res[fieldHalfopenInFlight]),
+		Successes:        parseInt(res[fieldSuccesses]),
+		Failures:         parseInt(res[fieldFailures]),
+		Timeouts:         parseInt(res[fieldTimeouts]),
+		Version:          parseInt(res[fieldVersion]),
+	}
+	return snap, nil
+}
+
+// parseInt parses a Redis hash field as an int64, treating a missing or
+// malformed field as 0 rather than erroring, since HGETALL omits absent
+// fields entirely.
+func parseInt(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
+
+// CompareAndSwapState implements goBreaker.StateStore.
+func (s *Store) CompareAndSwapState(key string, old, new goBreaker.StateSnapshot) (bool, error) {
+	ctx := context.Background()
+	res, err := casScript.Run(ctx, s.client, []string{key},
+		old.Version,
+		int(new.State),
+		new.OpenTime.UnixNano(),
+		new.LastRetryTime.UnixNano(),
+		new.HalfopenSuccess,
+		new.Successes,
+		new.Failures,
+		new.Timeouts,
+		new.HalfopenInFlight,
+	).Int()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// IncrCounters implements goBreaker.StateStore.
+func (s *Store) IncrCounters(key string, succ, fail, timeout int64, bucketTS int64) error {
+	ctx := context.Background()
+	_, err := incrScript.Run(ctx, s.client, []string{key},
+		bucketTS, succ, fail, s.WindowTime.Nanoseconds(), timeout,
+	).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	return nil
+}
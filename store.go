@@ -0,0 +1,178 @@
+package goBreaker
+
+import (
+	"sync"
+	"time"
+)
+
+// StateSnapshot is the serializable slice of breaker state that a StateStore
+// persists on behalf of a shared key, so many application instances can
+// agree on the same OPEN/HALFOPEN/CLOSED decision instead of each tripping
+// independently.
+type StateSnapshot struct {
+	State            State
+	OpenTime         time.Time // the time when the breaker became OPEN
+	LastRetryTime    time.Time // last retry time when in HALFOPEN state
+	HalfopenSuccess  int       // consecutive successes when HALFOPEN
+	HalfopenInFlight int64     // in-flight HALFOPEN probes, when HalfOpenMaxConcurrent is used
+
+	// Successes, Failures and Timeouts are the aggregate counters for the
+	// current window, maintained by IncrCounters.
+	Successes int64
+	Failures  int64
+	Timeouts  int64
+
+	// Version is bumped on every successful CompareAndSwapState and every
+	// IncrCounters call, and used to detect concurrent modification between
+	// a LoadState and a later CompareAndSwapState - including modification
+	// by an IncrCounters that landed in between, which would otherwise get
+	// silently overwritten by a CAS still carrying the older counters.
+	Version int64
+}
+
+// StateStore persists the mutable fields of a Breaker (state, timers,
+// half-open counters, window counts) behind a pluggable backend, so many
+// instances of an application can share the same breaker decision instead
+// of maintaining independent in-process windows. A Breaker only goes
+// through a StateStore when Options.Store is set; the zero value (nil) keeps
+// the default in-process behavior.
+type StateStore interface {
+	// LoadState returns the current snapshot for key. A store seeing key
+	// for the first time returns a snapshot whose State is CLOSED and whose
+	// other fields are zero, and a nil error.
+	LoadState(key string) (StateSnapshot, error)
+
+	// CompareAndSwapState atomically replaces the stored snapshot with new
+	// if it still matches old (by Version), returning false without error
+	// if it has since changed underneath the caller.
+	CompareAndSwapState(key string, old, new StateSnapshot) (bool, error)
+
+	// IncrCounters atomically adds succ/fail/timeout to key's window
+	// counters; bucketTS (unix nanoseconds) lets a store roll a sliding
+	// window instead of accumulating forever. Implementations must also bump
+	// the stored Version, so a CompareAndSwapState racing a concurrent
+	// IncrCounters sees its old snapshot as stale and retries instead of
+	// silently overwriting the newer counters.
+	IncrCounters(key string, succ, fail, timeout int64, bucketTS int64) error
+}
+
+// snapshotContainer adapts a StateSnapshot to the Container interface so the
+// existing TripFuncs (RateTripFunc, ThresholdTripFunc, ...) evaluate
+// store-backed, cross-instance counters unmodified. ConsecutiveErrors isn't
+// tracked across instances, so it always reads 0; use RateTripFunc or
+// ThresholdTripFunc rather than ConsecutiveTripFunc with a distributed store.
+type snapshotContainer struct {
+	snap StateSnapshot
+}
+
+func (c snapshotContainer) Fail()    {}
+func (c snapshotContainer) Succeed() {}
+func (c snapshotContainer) Timeout() {}
+
+func (c snapshotContainer) Failures() int64          { return c.snap.Failures }
+func (c snapshotContainer) Successes() int64         { return c.snap.Successes }
+func (c snapshotContainer) Timeouts() int64          { return c.snap.Timeouts }
+func (c snapshotContainer) ConsecutiveErrors() int64 { return 0 }
+
+// Observe is a no-op: StateSnapshot doesn't carry latency, so a distributed
+// breaker has no signal for AdaptiveTripFunc's latency EWMA and should be
+// paired with RateTripFunc or ThresholdTripFunc instead.
+func (c snapshotContainer) Observe(latency time.Duration, outcome Outcome) {}
+func (c snapshotContainer) AvgLatency() time.Duration                     { return 0 }
+
+func (c snapshotContainer) ErrorRate() float64 {
+	total := c.snap.Successes + c.snap.Failures + c.snap.Timeouts
+	if total == 0 {
+		return 0.0
+	}
+	return float64(c.snap.Failures+c.snap.Timeouts) / float64(total)
+}
+
+func (c snapshotContainer) Samples() int64 {
+	return c.snap.Successes + c.snap.Failures + c.snap.Timeouts
+}
+
+func (c snapshotContainer) Counts() (successes, failures, timeouts int64) {
+	return c.snap.Successes, c.snap.Failures, c.snap.Timeouts
+}
+
+func (c snapshotContainer) Reset() {}
+
+// memoryEntry is a key's stored snapshot plus the bucketTS IncrCounters last
+// rotated its window at; bucketTS isn't part of StateSnapshot since, like
+// redisstore's bucket_ts hash field, it's a store-internal rotation detail
+// and not part of what LoadState exposes to callers.
+type memoryEntry struct {
+	snap     StateSnapshot
+	bucketTS int64
+}
+
+// memoryStore is the in-memory StateStore, matching the pre-Store default
+// behavior but reachable through the same interface as redisstore.Store.
+type memoryStore struct {
+	mu    sync.Mutex
+	state map[string]*memoryEntry
+
+	// windowTime bounds how long IncrCounters accumulates into the same
+	// window before rotating it, mirroring redisstore.Store.WindowTime.
+	windowTime time.Duration
+}
+
+// NewMemoryStore creates a StateStore that keeps all snapshots in process
+// memory; useful for tests, or for giving every breaker a uniform StateStore
+// API even when no distributed backend is in play.
+func NewMemoryStore() StateStore {
+	return &memoryStore{state: map[string]*memoryEntry{}, windowTime: 10 * time.Second}
+}
+
+func (s *memoryStore) LoadState(key string) (StateSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.state[key]; ok {
+		return e.snap, nil
+	}
+	return StateSnapshot{State: CLOSED}, nil
+}
+
+func (s *memoryStore) CompareAndSwapState(key string, old, new StateSnapshot) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.state[key]
+	if ok && e.snap.Version != old.Version {
+		return false, nil
+	}
+	if !ok && old.Version != 0 {
+		return false, nil
+	}
+	new.Version = old.Version + 1
+	if !ok {
+		e = &memoryEntry{}
+		s.state[key] = e
+	}
+	e.snap = new
+	return true, nil
+}
+
+// IncrCounters rotates key's window once bucketTS has advanced past
+// windowTime since the window last rotated, same as redisstore's incrScript, and bumps
+// Version so a CompareAndSwapState racing this call sees its loaded snapshot
+// as stale (by Version) and retries with the incremented counters instead of
+// overwriting them with whatever it read before this call landed.
+func (s *memoryStore) IncrCounters(key string, succ, fail, timeout int64, bucketTS int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.state[key]
+	if !ok {
+		e = &memoryEntry{snap: StateSnapshot{State: CLOSED}}
+		s.state[key] = e
+	}
+	if bucketTS-e.bucketTS > s.windowTime.Nanoseconds() {
+		e.snap.Successes, e.snap.Failures, e.snap.Timeouts = 0, 0, 0
+		e.bucketTS = bucketTS
+	}
+	e.snap.Successes += succ
+	e.snap.Failures += fail
+	e.snap.Timeouts += timeout
+	e.snap.Version++
+	return nil
+}
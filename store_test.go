@@ -0,0 +1,166 @@
+package goBreaker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreCompareAndSwapVersioning(t *testing.T) {
+	s := NewMemoryStore()
+
+	snap, err := s.LoadState("k")
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if snap.State != CLOSED {
+		t.Fatalf("state = %v, want CLOSED for an unseen key", snap.State)
+	}
+
+	next := snap
+	next.State = OPEN
+	ok, err := s.CompareAndSwapState("k", snap, next)
+	if err != nil || !ok {
+		t.Fatalf("first CAS: ok=%v err=%v", ok, err)
+	}
+
+	// A second CAS against the now-stale snap (Version unchanged) must fail.
+	stale := snap
+	stale.State = HALFOPEN
+	ok, err = s.CompareAndSwapState("k", snap, stale)
+	if err != nil {
+		t.Fatalf("stale CAS: %v", err)
+	}
+	if ok {
+		t.Fatalf("stale CAS succeeded, want rejected due to version mismatch")
+	}
+
+	reloaded, err := s.LoadState("k")
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if reloaded.State != OPEN {
+		t.Fatalf("state = %v, want OPEN from the winning CAS", reloaded.State)
+	}
+}
+
+func TestMemoryStoreIncrCountersWithinWindowAccumulates(t *testing.T) {
+	s := NewMemoryStore()
+	base := time.Now().UnixNano()
+	if err := s.IncrCounters("k", 1, 0, 0, base); err != nil {
+		t.Fatalf("IncrCounters: %v", err)
+	}
+	if err := s.IncrCounters("k", 0, 1, 1, base+int64(time.Second)); err != nil {
+		t.Fatalf("IncrCounters: %v", err)
+	}
+
+	snap, err := s.LoadState("k")
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if snap.Successes != 1 || snap.Failures != 1 || snap.Timeouts != 1 {
+		t.Fatalf("counts = %+v, want 1/1/1", snap)
+	}
+}
+
+// TestMemoryStoreIncrCountersRotatesWindow checks that, like redisstore's
+// incrScript, memoryStore resets the window's counters once bucketTS has
+// advanced past windowTime since the window last rotated, instead of
+// accumulating for the breaker's entire lifetime.
+func TestMemoryStoreIncrCountersRotatesWindow(t *testing.T) {
+	s := NewMemoryStore()
+	base := time.Now().UnixNano()
+	if err := s.IncrCounters("k", 0, 1, 1, base); err != nil {
+		t.Fatalf("IncrCounters: %v", err)
+	}
+
+	if err := s.IncrCounters("k", 1, 0, 0, base+int64(20*time.Second)); err != nil {
+		t.Fatalf("IncrCounters: %v", err)
+	}
+
+	snap, err := s.LoadState("k")
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if snap.Successes != 1 || snap.Failures != 0 || snap.Timeouts != 0 {
+		t.Fatalf("counts = %+v, want reset to 1/0/0 once the window rolled over", snap)
+	}
+}
+
+// TestMemoryStoreIncrCountersBumpsVersionAgainstConcurrentCAS reproduces the
+// lost-update race between IncrCounters and CompareAndSwapState:
+// errorDistributed/succeedDistributed call IncrCounters, then LoadState,
+// then conditionally CompareAndSwapState with a snapshot copy carrying the
+// counters as they were at LoadState time. If IncrCounters didn't bump
+// Version, a concurrent IncrCounters landing after that LoadState would go
+// undetected and the CAS would silently overwrite it with the older counts.
+func TestMemoryStoreIncrCountersBumpsVersionAgainstConcurrentCAS(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Now().UnixNano()
+
+	if err := s.IncrCounters("k", 1, 0, 0, now); err != nil {
+		t.Fatalf("IncrCounters: %v", err)
+	}
+
+	// A caller (e.g. errorDistributed) loads the snapshot it'll base its CAS
+	// on here...
+	loaded, err := s.LoadState("k")
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+
+	// ...but another instance's IncrCounters lands before the CAS does.
+	if err := s.IncrCounters("k", 1, 0, 0, now+1); err != nil {
+		t.Fatalf("IncrCounters: %v", err)
+	}
+
+	stale := loaded
+	stale.State = OPEN
+	ok, err := s.CompareAndSwapState("k", loaded, stale)
+	if err != nil {
+		t.Fatalf("CompareAndSwapState: %v", err)
+	}
+	if ok {
+		t.Fatalf("CAS against a snapshot staled by a concurrent IncrCounters succeeded, want rejected")
+	}
+
+	fresh, err := s.LoadState("k")
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if fresh.Successes != 2 {
+		t.Fatalf("successes = %d, want 2 (both IncrCounters calls) - not overwritten by the rejected stale CAS", fresh.Successes)
+	}
+}
+
+// TestDistributedHalfOpenMaxConcurrentBoundsFirstProbe mirrors
+// TestHalfOpenMaxConcurrentBoundsFirstProbe, but against a Store-backed
+// Breaker, to check the CAS-based isAllowedDistributed applies
+// HalfOpenMaxConcurrent to the OPEN->HALFOPEN transition too.
+func TestDistributedHalfOpenMaxConcurrentBoundsFirstProbe(t *testing.T) {
+	b, err := NewBreaker(Options{
+		CoolingTimeout:        time.Millisecond,
+		HalfOpenMaxConcurrent: 2,
+		Store:                 NewMemoryStore(),
+		StoreKey:              "breaker",
+	})
+	if err != nil {
+		t.Fatalf("NewBreaker: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		b.Fail()
+	}
+
+	time.Sleep(2 * time.Millisecond)
+
+	admitted := 0
+	for i := 0; i < 5; i++ {
+		if ok, _ := b.IsAllowedErr(); ok {
+			admitted++
+		}
+	}
+
+	if admitted != 2 {
+		t.Fatalf("admitted = %d, want 2 (HalfOpenMaxConcurrent)", admitted)
+	}
+}